@@ -0,0 +1,56 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fm
+
+// AudioWriter is the audio-output half of sdr.Writer: something that can
+// accept a stream of demodulated audio samples. Implementations live in
+// the fm/audio/* subpackages (wav, raw, portaudio).
+type AudioWriter interface {
+	// Write accepts demodulated audio samples.
+	Write([]float32) (int, error)
+
+	// SampleRate returns the rate, in samples/sec, the writer expects.
+	SampleRate() uint
+
+	// Close flushes and releases any resources held by the writer.
+	Close() error
+}
+
+// audioBufLen is the chunk size Pump reads from the Demodulator at a time.
+const audioBufLen = 4096
+
+// Pump reads demodulated audio from d and writes it to w until d.Read
+// returns an error (including io.EOF), which it returns to the caller.
+func Pump(d *Demodulator, w AudioWriter) error {
+	buf := make([]float32, audioBufLen)
+	for {
+		n, err := d.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// vim: foldmethod=marker
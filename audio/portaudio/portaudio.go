@@ -0,0 +1,141 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build portaudio
+
+// Package portaudio implements fm.AudioWriter as live playback through
+// PortAudio. It's behind the "portaudio" build tag since it requires the
+// PortAudio native library to be installed, unlike the rest of this
+// module.
+package portaudio
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// ringSize is the capacity, in samples, of the ring buffer between Write
+// (called from the demodulator's goroutine) and the PortAudio callback
+// (called from PortAudio's own audio thread).
+const ringSize = 1 << 16
+
+// Writer plays audio live through the default PortAudio output device. A
+// ring buffer decouples the producer (Write) from PortAudio's realtime
+// callback, so a slow producer causes reported Underruns rather than
+// blocking or glitching silently.
+type Writer struct {
+	stream     *portaudio.Stream
+	sampleRate uint
+
+	mu        sync.Mutex
+	ring      []float32
+	head      int // next slot Write will fill
+	tail      int // next slot the callback will read
+	count     int
+	Underruns uint64
+}
+
+// NewWriter opens the default PortAudio output device at sampleRate
+// samples/sec, mono, and starts playback.
+func NewWriter(sampleRate uint) (*Writer, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, fmt.Errorf("fm/audio/portaudio: %w", err)
+	}
+
+	w := &Writer{
+		sampleRate: sampleRate,
+		ring:       make([]float32, ringSize),
+	}
+
+	stream, err := portaudio.OpenDefaultStream(0, 1, float64(sampleRate), 0, w.callback)
+	if err != nil {
+		portaudio.Terminate()
+		return nil, fmt.Errorf("fm/audio/portaudio: %w", err)
+	}
+	w.stream = stream
+
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		portaudio.Terminate()
+		return nil, fmt.Errorf("fm/audio/portaudio: %w", err)
+	}
+
+	return w, nil
+}
+
+// callback is invoked on PortAudio's realtime thread to pull the next
+// block of samples out of the ring buffer.
+func (w *Writer) callback(out []float32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range out {
+		if w.count == 0 {
+			w.Underruns++
+			out[i] = 0
+			continue
+		}
+		out[i] = w.ring[w.tail]
+		w.tail = (w.tail + 1) % len(w.ring)
+		w.count--
+	}
+}
+
+// SampleRate implements the fm.AudioWriter interface.
+func (w *Writer) SampleRate() uint {
+	return w.sampleRate
+}
+
+// Write implements the fm.AudioWriter interface, pushing samples into the
+// ring buffer. If the buffer is full, Write drops the oldest unplayed
+// samples to make room, rather than blocking the caller indefinitely.
+func (w *Writer) Write(samples []float32) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, s := range samples {
+		if w.count == len(w.ring) {
+			// Buffer's full; drop the oldest sample to make room so a
+			// slow consumer doesn't stall the whole pipeline.
+			w.tail = (w.tail + 1) % len(w.ring)
+			w.count--
+		}
+		w.ring[w.head] = s
+		w.head = (w.head + 1) % len(w.ring)
+		w.count++
+	}
+
+	return len(samples), nil
+}
+
+// Close stops playback and releases PortAudio resources.
+func (w *Writer) Close() error {
+	if err := w.stream.Stop(); err != nil {
+		return err
+	}
+	if err := w.stream.Close(); err != nil {
+		return err
+	}
+	return portaudio.Terminate()
+}
+
+// vim: foldmethod=marker
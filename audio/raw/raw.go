@@ -0,0 +1,84 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package raw implements fm.AudioWriter as headerless signed 16-bit
+// little-endian PCM, suitable for piping straight into `aplay` or
+// `ffmpeg`.
+package raw
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Writer writes signed 16-bit little-endian PCM samples to w, clipping to
+// [-1, 1] before scaling to the int16 range.
+type Writer struct {
+	w          io.Writer
+	sampleRate uint
+	buf        []byte
+}
+
+// NewWriter creates a Writer writing to w at sampleRate samples/sec. w is
+// closed on Close if it implements io.Closer.
+func NewWriter(w io.Writer, sampleRate uint) *Writer {
+	return &Writer{w: w, sampleRate: sampleRate}
+}
+
+// SampleRate implements the fm.AudioWriter interface.
+func (w *Writer) SampleRate() uint {
+	return w.sampleRate
+}
+
+// Write implements the fm.AudioWriter interface.
+func (w *Writer) Write(samples []float32) (int, error) {
+	if cap(w.buf) < len(samples)*2 {
+		w.buf = make([]byte, len(samples)*2)
+	}
+	buf := w.buf[:len(samples)*2]
+
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(int16(clip(s)*32767)))
+	}
+
+	n, err := w.w.Write(buf)
+	return n / 2, err
+}
+
+func clip(s float32) float32 {
+	switch {
+	case s > 1:
+		return 1
+	case s < -1:
+		return -1
+	default:
+		return s
+	}
+}
+
+// Close closes the underlying writer, if it's an io.Closer.
+func (w *Writer) Close() error {
+	if closer, ok := w.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,163 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package wav implements fm.AudioWriter as a RIFF/WAVE file, in mono or
+// (for use with fm.StereoDemodulator) stereo.
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// headerSize is the size, in bytes, of everything before the "data" chunk
+// body: the RIFF header, the "WAVE" tag, the "fmt " chunk, and the "data"
+// chunk's own id and size.
+const headerSize = 44
+
+// Writer writes signed 16-bit PCM samples to a RIFF/WAVE file, patching
+// the RIFF and data chunk sizes on Close once the final length is known.
+type Writer struct {
+	w          io.WriteSeeker
+	sampleRate uint
+	channels   int
+
+	dataBytes uint32
+}
+
+// NewWriter writes a placeholder WAVE header to w (sampleRate samples/sec,
+// channels channels, 16-bit PCM) and returns a Writer ready to accept
+// sample data. w must support Seek, since the header is patched on Close.
+func NewWriter(w io.WriteSeeker, sampleRate uint, channels int) (*Writer, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("wav: channels must be >= 1")
+	}
+
+	blockAlign := channels * 2 // 16-bit samples
+	byteRate := uint32(sampleRate) * uint32(blockAlign)
+
+	header := make([]byte, headerSize)
+	copy(header[0:4], "RIFF")
+	// header[4:8] (RIFF size) patched on Close.
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(header[20:22], 1)  // PCM
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], byteRate)
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], 16) // bits per sample
+	copy(header[36:40], "data")
+	// header[40:44] (data size) patched on Close.
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		w:          w,
+		sampleRate: sampleRate,
+		channels:   channels,
+	}, nil
+}
+
+// SampleRate implements the fm.AudioWriter interface.
+func (w *Writer) SampleRate() uint {
+	return w.sampleRate
+}
+
+// Write implements the fm.AudioWriter interface, writing a mono (or, if
+// Writer was created with channels > 1, already-interleaved) stream of
+// samples.
+func (w *Writer) Write(samples []float32) (int, error) {
+	return w.write(samples)
+}
+
+// WriteStereo interleaves left and right (which must be the same length)
+// and writes them out. Writer must have been created with channels == 2.
+func (w *Writer) WriteStereo(left, right []float32) (int, error) {
+	if w.channels != 2 {
+		return 0, fmt.Errorf("wav: WriteStereo called on a %d channel Writer", w.channels)
+	}
+	if len(left) != len(right) {
+		return 0, fmt.Errorf("wav: left and right must be the same length")
+	}
+
+	interleaved := make([]float32, 2*len(left))
+	for i := range left {
+		interleaved[2*i] = left[i]
+		interleaved[2*i+1] = right[i]
+	}
+
+	n, err := w.write(interleaved)
+	return n / 2, err
+}
+
+func (w *Writer) write(samples []float32) (int, error) {
+	buf := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(int16(clip(s)*32767)))
+	}
+
+	n, err := w.w.Write(buf)
+	w.dataBytes += uint32(n)
+	return n / 2, err
+}
+
+func clip(s float32) float32 {
+	switch {
+	case s > 1:
+		return 1
+	case s < -1:
+		return -1
+	default:
+		return s
+	}
+}
+
+// Close patches the RIFF and data chunk sizes with the final length, now
+// that it's known.
+func (w *Writer) Close() error {
+	if _, err := w.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	var sizes [4]byte
+	binary.LittleEndian.PutUint32(sizes[:], 36+w.dataBytes)
+	if _, err := w.w.Write(sizes[:]); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Seek(40, io.SeekStart); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(sizes[:], w.dataBytes)
+	if _, err := w.w.Write(sizes[:]); err != nil {
+		return err
+	}
+
+	if closer, ok := w.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,87 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fm
+
+import "math"
+
+// Deemphasis is a de-emphasis (or, on the Modulator, pre-emphasis) time
+// constant, tau, in seconds. A zero value disables the filter.
+type Deemphasis float64
+
+var (
+	// Deemphasis75us is the 75 microsecond time constant used for FM
+	// broadcast in the Americas and Korea.
+	Deemphasis75us Deemphasis = 75e-6
+
+	// Deemphasis50us is the 50 microsecond time constant used for FM
+	// broadcast in most of the rest of the world.
+	Deemphasis50us Deemphasis = 50e-6
+)
+
+// deemphasisFilter is a one-pole de-emphasis IIR filter:
+//
+//	y[n] = a*x[n] + (1-a)*y[n-1], with a = 1 - exp(-1/(tau*fs))
+//
+// It holds its own state, so one is needed per audio channel.
+type deemphasisFilter struct {
+	a     float64
+	state float32
+}
+
+func newDeemphasisFilter(tau Deemphasis, audioSampleRate float64) *deemphasisFilter {
+	if tau == 0 {
+		return nil
+	}
+	return &deemphasisFilter{
+		a: 1 - math.Exp(-1/(float64(tau)*audioSampleRate)),
+	}
+}
+
+func (f *deemphasisFilter) Step(x float32) float32 {
+	f.state += float32(f.a) * (x - f.state)
+	return f.state
+}
+
+// preemphasisFilter is the inverse one-zero filter used on the Modulator
+// side before modulation:
+//
+//	y[n] = (x[n] - (1-a)*x[n-1]) / a, with a = 1 - exp(-1/(tau*fs))
+type preemphasisFilter struct {
+	a    float64
+	last float32
+}
+
+func newPreemphasisFilter(tau Deemphasis, audioSampleRate float64) *preemphasisFilter {
+	if tau == 0 {
+		return nil
+	}
+	return &preemphasisFilter{
+		a: 1 - math.Exp(-1/(float64(tau)*audioSampleRate)),
+	}
+}
+
+func (f *preemphasisFilter) Step(x float32) float32 {
+	y := (x - float32(1-f.a)*f.last) / float32(f.a)
+	f.last = x
+	return y
+}
+
+// vim: foldmethod=marker
@@ -21,14 +21,18 @@
 package fm
 
 import (
-	"math/cmplx"
-
-	"hz.tools/fftw"
-	"hz.tools/fm/internal"
+	"hz.tools/fm/filter"
+	"hz.tools/fm/resample"
 	"hz.tools/rf"
 	"hz.tools/sdr"
-	"hz.tools/sdr/fft"
-	"hz.tools/sdr/stream"
+)
+
+// defaultTransitionBandwidth and defaultStopbandAttenuationDB pick a
+// reasonable channel-select filter when a DemodulatorConfig doesn't set
+// them explicitly.
+const (
+	defaultTransitionBandwidth   rf.Hz = rf.KHz * 10
+	defaultStopbandAttenuationDB       = 60.0
 )
 
 // Reader will allow for the reading of FM demodulated audio samples from
@@ -57,15 +61,45 @@ type DemodulatorConfig struct {
 	// frequencies. This is half of the total bandwidth.
 	Deviation rf.Hz
 
-	// Downsample will define rate to downsample the samples to bring it to
-	// a sensible audio sample rate.
-	Downsample int
+	// AudioSampleRate is the target audio sample rate. Unlike the old
+	// integer Downsample field, this doesn't need to divide evenly into
+	// the IQ sample rate: Demodulate builds a polyphase rational
+	// resampler (see hz.tools/fm/resample) to get there.
+	AudioSampleRate rf.Hz
+
+	// Deemphasis is the de-emphasis time constant to apply to the
+	// demodulated audio. Use Deemphasis75us or Deemphasis50us, or leave
+	// this at 0 to disable de-emphasis entirely.
+	Deemphasis Deemphasis
+
+	// TransitionBandwidth controls how sharply the channel-select filter
+	// rolls off past Deviation; narrower means more FIR taps. Leave at 0
+	// to use a sensible default (10 KHz).
+	TransitionBandwidth rf.Hz
+
+	// StopbandAttenuationDB controls how deep the channel-select filter's
+	// stopband rejection is. Leave at 0 to use a sensible default (60 dB).
+	StopbandAttenuationDB float64
+
+	// UseFFTW selects the legacy frequency-domain channel-select filter,
+	// which requires FFTW to be installed. By default, Demodulate uses a
+	// windowed-sinc FIR filter (see hz.tools/fm/filter) that has no such
+	// dependency. Binaries not built with the "fftw" build tag will get
+	// an error from Demodulate if UseFFTW is set.
+	UseFFTW bool
+
+	// Discriminator selects how audio samples are recovered from the
+	// channel-selected IQ stream. Leave nil to use DiscriminatorArctan,
+	// the original (and cheaper) behavior.
+	Discriminator Discriminator
 }
 
 // Demodulator contains info about
 type Demodulator struct {
 	reader sdr.Reader
 	config DemodulatorConfig
+	deemph *deemphasisFilter
+	disc   discriminatorState
 }
 
 // Reader will return the underlying reader (TODO: Remove this)
@@ -90,9 +124,19 @@ func (d Demodulator) Read(audio []float32) (int, error) {
 	for i := 1; i < len(buf); i++ {
 		phasor := complex128(buf[i])
 		lastPhasor := complex128(buf[i-1])
-		audio[i] = float32(cmplx.Phase(phasor * cmplx.Conj(lastPhasor)))
+		audio[i] = d.disc.step(phasor, lastPhasor)
+		if d.disc.squelched() {
+			audio[i] = 0
+		}
 	}
 	audio[0] = audio[1]
+
+	if d.deemph != nil {
+		for i := range audio[:len(buf)] {
+			audio[i] = d.deemph.Step(audio[i])
+		}
+	}
+
 	return len(buf), nil
 }
 
@@ -107,30 +151,43 @@ func Demodulate(reader sdr.Reader, cfg DemodulatorConfig) (*Demodulator, error)
 		return nil, sdr.ErrSampleFormatMismatch
 	}
 
-	filter := make([]complex64, 1024*64)
-	if err := internal.Filter(
-		filter,
-		reader.SampleRate(),
-		fft.ZeroFirst,
-		cfg.CenterFrequency,
-		cfg.Deviation,
-	); err != nil {
-		return nil, err
+	transitionBW := cfg.TransitionBandwidth
+	if transitionBW == 0 {
+		transitionBW = defaultTransitionBandwidth
+	}
+	stopbandAttenuationDB := cfg.StopbandAttenuationDB
+	if stopbandAttenuationDB == 0 {
+		stopbandAttenuationDB = defaultStopbandAttenuationDB
 	}
 
-	reader, err = stream.ConvolutionReader(reader, fftw.Plan, filter)
-	if err != nil {
-		return nil, err
+	if cfg.UseFFTW {
+		reader, err = fftwChannelSelect(reader, cfg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		taps := filter.Channel(reader.SampleRate(), cfg.CenterFrequency, cfg.Deviation, transitionBW, stopbandAttenuationDB)
+		reader, err = filter.NewReader(reader, taps)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	reader, err = stream.DownsampleReader(reader, cfg.Downsample)
+	reader, err = resample.NewReader(reader, cfg.AudioSampleRate)
 	if err != nil {
 		return nil, err
 	}
 
+	disc := cfg.Discriminator
+	if disc == nil {
+		disc = DiscriminatorArctan
+	}
+
 	return &Demodulator{
 		reader: reader,
 		config: cfg,
+		deemph: newDeemphasisFilter(cfg.Deemphasis, float64(reader.SampleRate())),
+		disc:   disc.newState(float64(reader.SampleRate())),
 	}, nil
 }
 
@@ -0,0 +1,52 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+//go:build fftw
+
+package fm
+
+import (
+	"hz.tools/fftw"
+	"hz.tools/fm/internal"
+	"hz.tools/sdr"
+	"hz.tools/sdr/fft"
+	"hz.tools/sdr/stream"
+)
+
+// fftwChannelSelect builds the legacy frequency-domain channel-select
+// filter, stamping bins directly rather than designing a FIR prototype.
+// It requires the FFTW C library, which is why it's behind the "fftw"
+// build tag.
+func fftwChannelSelect(reader sdr.Reader, cfg DemodulatorConfig) (sdr.Reader, error) {
+	taps := make([]complex64, 1024*64)
+	if err := internal.Filter(
+		taps,
+		reader.SampleRate(),
+		fft.ZeroFirst,
+		cfg.CenterFrequency,
+		cfg.Deviation,
+	); err != nil {
+		return nil, err
+	}
+
+	return stream.ConvolutionReader(reader, fftw.Plan, taps)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,133 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fm
+
+import (
+	"math/cmplx"
+
+	"hz.tools/fm/internal"
+)
+
+// Discriminator selects how the Demodulator turns consecutive IQ samples
+// into an instantaneous frequency (audio) sample.
+type Discriminator interface {
+	newState(sampleRate float64) discriminatorState
+}
+
+// discriminatorState holds whatever per-stream state a Discriminator
+// needs, and does the actual sample-by-sample work.
+type discriminatorState interface {
+	// step consumes one new phasor (and the one before it) and returns
+	// the demodulated audio sample.
+	step(phasor, lastPhasor complex128) float32
+
+	// squelched reports whether this sample should be muted. Only the PLL
+	// discriminator currently has a meaningful answer; arctan always
+	// returns false.
+	squelched() bool
+}
+
+// arctanDiscriminator is fm.DiscriminatorArctan: phase(z[n]*conj(z[n-1])),
+// the original (and still default) discriminator.
+type arctanDiscriminator struct{}
+
+// DiscriminatorArctan is the classic arctan-differencing FM discriminator.
+var DiscriminatorArctan Discriminator = arctanDiscriminator{}
+
+func (arctanDiscriminator) newState(sampleRate float64) discriminatorState {
+	return &arctanState{}
+}
+
+type arctanState struct{}
+
+func (*arctanState) step(phasor, lastPhasor complex128) float32 {
+	return float32(cmplx.Phase(phasor * cmplx.Conj(lastPhasor)))
+}
+
+func (*arctanState) squelched() bool {
+	return false
+}
+
+// DiscriminatorPLL demodulates by tracking the input frequency with a
+// second-order PLL instead of differencing phase directly: the NCO's
+// frequency output is the demodulated audio. This gives much better
+// weak-signal performance than arctan differencing, at the cost of loop
+// acquisition/lag.
+type DiscriminatorPLL struct {
+	// LoopBW is the PLL loop bandwidth, in Hz. Wider tracks faster but
+	// passes more noise through; narrower is quieter but slower to
+	// acquire and more prone to cycle slips on deep fades.
+	LoopBW float64
+
+	// DampingFactor controls the loop's damping; 0.707 (critically
+	// damped) is a reasonable default.
+	DampingFactor float64
+
+	// Squelch, if non-zero, mutes audio output whenever the loop's
+	// smoothed phase-error magnitude exceeds this threshold (radians) -
+	// a simple proxy for "the PLL has lost lock because the carrier
+	// dropped out."
+	Squelch float64
+}
+
+func (d DiscriminatorPLL) newState(sampleRate float64) discriminatorState {
+	return &pllState{
+		pll:     internal.NewPLL(sampleRate, 0, d.LoopBW, d.DampingFactor),
+		errLPF:  internal.NewOnePoleLowpass(10, sampleRate),
+		squelch: d.Squelch,
+	}
+}
+
+type pllState struct {
+	pll     *internal.PLL
+	errLPF  *internal.OnePoleLowpass
+	squelch float64
+
+	lastErr float64
+}
+
+func (p *pllState) step(phasor, lastPhasor complex128) float32 {
+	// Mix the input down by the NCO's current estimate; the imaginary
+	// part of the result is (approximately, for small phase error) the
+	// phase error the loop filter needs to null.
+	mixed := phasor * cmplx.Conj(cmplx.Exp(complex(0, p.pll.Phase())))
+	err := imag(mixed)
+
+	p.pll.Step(err)
+	p.lastErr = p.errLPF.Step(absFloat(err))
+
+	// pll.Freq() is in radians/sample, the same units as the arctan
+	// discriminator's phase-difference-per-sample output.
+	return float32(p.pll.Freq())
+}
+
+func (p *pllState) squelched() bool {
+	return p.squelch != 0 && p.lastErr > p.squelch
+}
+
+func absFloat(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// vim: foldmethod=marker
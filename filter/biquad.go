@@ -0,0 +1,188 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import "math"
+
+// Biquad is a single second-order IIR section, run in direct-form II
+// transposed (the usual choice for floating point audio, since it has
+// lower coefficient sensitivity than direct-form I/II).
+type Biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	z1, z2     float64
+}
+
+// Step filters a single sample.
+func (b *Biquad) Step(x float64) float64 {
+	y := b.b0*x + b.z1
+	b.z1 = b.b1*x - b.a1*y + b.z2
+	b.z2 = b.b2*x - b.a2*y
+	return y
+}
+
+// Cascade is a chain of Biquad sections, run in series. This is how all of
+// the coefficient helpers below (other than a single LPF/HPF, which need
+// just one section) should be combined for steeper rolloff.
+type Cascade []*Biquad
+
+// Step runs x through every section of the cascade in order.
+func (c Cascade) Step(x float64) float64 {
+	for _, b := range c {
+		x = b.Step(x)
+	}
+	return x
+}
+
+// newBiquad normalizes a set of analog-prototype-derived coefficients by
+// a0, producing a ready-to-run Biquad.
+func newBiquad(b0, b1, b2, a0, a1, a2 float64) *Biquad {
+	return &Biquad{
+		b0: b0 / a0,
+		b1: b1 / a0,
+		b2: b2 / a0,
+		a1: a1 / a0,
+		a2: a2 / a0,
+	}
+}
+
+// LPF designs a single second-order lowpass Biquad, using the Robert
+// Bristow-Johnson "Audio EQ Cookbook" formulas.
+func LPF(freq, sampleRate, q float64) *Biquad {
+	w0, alpha := biquadParams(freq, sampleRate, q)
+	cosw0 := math.Cos(w0)
+
+	b0 := (1 - cosw0) / 2
+	b1 := 1 - cosw0
+	b2 := (1 - cosw0) / 2
+	a0 := 1 + alpha
+	a1 := -2 * cosw0
+	a2 := 1 - alpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// HPF designs a single second-order highpass Biquad.
+func HPF(freq, sampleRate, q float64) *Biquad {
+	w0, alpha := biquadParams(freq, sampleRate, q)
+	cosw0 := math.Cos(w0)
+
+	b0 := (1 + cosw0) / 2
+	b1 := -(1 + cosw0)
+	b2 := (1 + cosw0) / 2
+	a0 := 1 + alpha
+	a1 := -2 * cosw0
+	a2 := 1 - alpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// BPF designs a constant-skirt-gain second-order bandpass Biquad centered
+// at freq with the given Q (higher Q means a narrower passband).
+func BPF(freq, sampleRate, q float64) *Biquad {
+	w0, alpha := biquadParams(freq, sampleRate, q)
+	cosw0 := math.Cos(w0)
+
+	b0 := alpha
+	b1 := 0.0
+	b2 := -alpha
+	a0 := 1 + alpha
+	a1 := -2 * cosw0
+	a2 := 1 - alpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// Notch designs a second-order notch (band-reject) Biquad at freq.
+func Notch(freq, sampleRate, q float64) *Biquad {
+	w0, alpha := biquadParams(freq, sampleRate, q)
+	cosw0 := math.Cos(w0)
+
+	b0 := 1.0
+	b1 := -2 * cosw0
+	b2 := 1.0
+	a0 := 1 + alpha
+	a1 := -2 * cosw0
+	a2 := 1 - alpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// Peaking designs a second-order peaking EQ Biquad at freq, boosting or
+// cutting by gainDB.
+func Peaking(freq, sampleRate, q, gainDB float64) *Biquad {
+	w0, alpha := biquadParams(freq, sampleRate, q)
+	cosw0 := math.Cos(w0)
+	a := math.Pow(10, gainDB/40)
+
+	b0 := 1 + alpha*a
+	b1 := -2 * cosw0
+	b2 := 1 - alpha*a
+	a0 := 1 + alpha/a
+	a1 := -2 * cosw0
+	a2 := 1 - alpha/a
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// LowShelf designs a second-order low-shelf Biquad with corner frequency
+// freq, shelf slope s (1.0 is a reasonable default), and gainDB of
+// boost/cut.
+func LowShelf(freq, sampleRate, s, gainDB float64) *Biquad {
+	w0, _ := biquadParams(freq, sampleRate, 1)
+	cosw0 := math.Cos(w0)
+	sinw0 := math.Sin(w0)
+	a := math.Pow(10, gainDB/40)
+	alpha := sinw0 / 2 * math.Sqrt((a+1/a)*(1/s-1)+2)
+	twoSqrtAAlpha := 2 * math.Sqrt(a) * alpha
+
+	b0 := a * ((a + 1) - (a-1)*cosw0 + twoSqrtAAlpha)
+	b1 := 2 * a * ((a - 1) - (a+1)*cosw0)
+	b2 := a * ((a + 1) - (a-1)*cosw0 - twoSqrtAAlpha)
+	a0 := (a + 1) + (a-1)*cosw0 + twoSqrtAAlpha
+	a1 := -2 * ((a - 1) + (a+1)*cosw0)
+	a2 := (a + 1) + (a-1)*cosw0 - twoSqrtAAlpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// HighShelf designs a second-order high-shelf Biquad, the highpass
+// counterpart to LowShelf.
+func HighShelf(freq, sampleRate, s, gainDB float64) *Biquad {
+	w0, _ := biquadParams(freq, sampleRate, 1)
+	cosw0 := math.Cos(w0)
+	sinw0 := math.Sin(w0)
+	a := math.Pow(10, gainDB/40)
+	alpha := sinw0 / 2 * math.Sqrt((a+1/a)*(1/s-1)+2)
+	twoSqrtAAlpha := 2 * math.Sqrt(a) * alpha
+
+	b0 := a * ((a + 1) + (a-1)*cosw0 + twoSqrtAAlpha)
+	b1 := -2 * a * ((a - 1) + (a+1)*cosw0)
+	b2 := a * ((a + 1) + (a-1)*cosw0 - twoSqrtAAlpha)
+	a0 := (a + 1) - (a-1)*cosw0 + twoSqrtAAlpha
+	a1 := 2 * ((a - 1) - (a+1)*cosw0)
+	a2 := (a + 1) - (a-1)*cosw0 - twoSqrtAAlpha
+	return newBiquad(b0, b1, b2, a0, a1, a2)
+}
+
+// biquadParams computes the shared w0/alpha terms used by every coefficient
+// helper above.
+func biquadParams(freq, sampleRate, q float64) (w0, alpha float64) {
+	w0 = 2 * math.Pi * freq / sampleRate
+	alpha = math.Sin(w0) / (2 * q)
+	return w0, alpha
+}
+
+// vim: foldmethod=marker
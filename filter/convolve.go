@@ -0,0 +1,111 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"hz.tools/sdr"
+)
+
+// reader performs streaming time-domain FIR convolution without needing
+// FFTW, using the overlap-save method: each output block is produced from
+// a window of input that includes len(taps)-1 samples of history from the
+// previous block, and every output sample is computed directly in the time
+// domain from that window, so no wrapped outputs are produced or discarded.
+type reader struct {
+	reader sdr.Reader
+	taps   []complex64
+
+	blockLen int
+	history  []complex64
+	in       sdr.SamplesC64
+}
+
+// NewReader wraps reader, convolving every sample read through it against
+// taps using the overlap-save method. This is the default channel-select
+// path used by Demodulate, and doesn't require FFTW to be installed.
+func NewReader(r sdr.Reader, taps []complex64) (sdr.Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+
+	blockLen := 4 * len(taps)
+	return &reader{
+		reader:   r,
+		taps:     taps,
+		blockLen: blockLen,
+		history:  make([]complex64, len(taps)-1),
+		in:       make(sdr.SamplesC64, blockLen),
+	}, nil
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (c *reader) SampleRate() uint {
+	return c.reader.SampleRate()
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (c *reader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// Close implements the sdr.Reader interface.
+func (c *reader) Close() error {
+	return c.reader.Close()
+}
+
+// Read implements the sdr.Reader interface.
+func (c *reader) Read(iqBuf sdr.Samples) (int, error) {
+	buf, ok := iqBuf.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	n := len(buf)
+	if n > c.blockLen {
+		n = c.blockLen
+		buf = buf[:n]
+	}
+
+	i, err := sdr.ReadFull(c.reader, c.in[:n])
+	if err != nil {
+		return 0, err
+	}
+
+	window := append(append([]complex64{}, c.history...), c.in[:i]...)
+	for outIdx := 0; outIdx < i; outIdx++ {
+		var sum complex64
+		for k, tap := range c.taps {
+			sum += window[outIdx+len(c.taps)-1-k] * tap
+		}
+		buf[outIdx] = sum
+	}
+
+	if i >= len(c.history) {
+		copy(c.history, c.in[i-len(c.history):i])
+	} else {
+		copy(c.history, c.history[i:])
+		copy(c.history[len(c.history)-i:], c.in[:i])
+	}
+
+	return i, nil
+}
+
+// vim: foldmethod=marker
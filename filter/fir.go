@@ -0,0 +1,102 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+
+	"hz.tools/rf"
+)
+
+// Order computes a windowed-sinc FIR order (number of taps) from a desired
+// transition bandwidth and stopband attenuation, using the standard Kaiser
+// length estimate. The result is rounded up to the next odd number, so the
+// filter has a well-defined integer group delay.
+func Order(sampleRate uint, transitionBW rf.Hz, stopbandAttenuationDB float64) int {
+	n := int(math.Ceil((stopbandAttenuationDB - 8) / (2.285 * 2 * math.Pi * (float64(transitionBW) / float64(sampleRate)))))
+	if n < 1 {
+		n = 1
+	}
+	if n%2 == 0 {
+		n++
+	}
+	return n
+}
+
+// Lowpass designs a windowed-sinc FIR lowpass filter with the given cutoff
+// frequency, returning numTaps real-valued (but complex64-typed, for direct
+// use with complex IQ convolution) filter taps. stopbandAttenuationDB is
+// only consulted for window == Kaiser, to pick the window's Beta shape
+// parameter (see KaiserBeta); it's ignored by the other windows.
+func Lowpass(numTaps int, cutoff rf.Hz, sampleRate uint, window Window, stopbandAttenuationDB float64) []complex64 {
+	var beta float64
+	if window == Kaiser {
+		beta = KaiserBeta(stopbandAttenuationDB)
+	}
+	return lowpass(numTaps, cutoff, sampleRate, window, beta)
+}
+
+func lowpass(numTaps int, cutoff rf.Hz, sampleRate uint, window Window, kaiserBeta float64) []complex64 {
+	taps := make([]complex64, numTaps)
+	win := window.apply(numTaps, kaiserBeta)
+
+	fc := float64(cutoff) / float64(sampleRate)
+	m := float64(numTaps - 1)
+
+	var sum float64
+	sinc := make([]float64, numTaps)
+	for i := range sinc {
+		x := float64(i) - m/2
+		if x == 0 {
+			sinc[i] = 2 * fc
+		} else {
+			sinc[i] = math.Sin(2*math.Pi*fc*x) / (math.Pi * x)
+		}
+		sinc[i] *= win[i]
+		sum += sinc[i]
+	}
+
+	// Normalize for unity gain at DC.
+	for i, v := range sinc {
+		taps[i] = complex64(complex(v/sum, 0))
+	}
+	return taps
+}
+
+// Channel designs a windowed-sinc FIR bandpass filter centered at cf with
+// half-bandwidth dv (i.e. it passes [cf-dv, cf+dv]), by frequency-shifting
+// a real lowpass prototype with a complex exponential. This replaces
+// stamping bins directly in the frequency domain for channel selection.
+func Channel(sampleRate uint, cf, dv rf.Hz, transitionBW rf.Hz, stopbandAttenuationDB float64) []complex64 {
+	beta := KaiserBeta(stopbandAttenuationDB)
+	n := Order(sampleRate, transitionBW, stopbandAttenuationDB)
+	lp := lowpass(n, dv, sampleRate, Kaiser, beta)
+
+	taps := make([]complex64, n)
+	for i := range taps {
+		shift := cmplx.Exp(complex(0, 2*math.Pi*float64(cf)*float64(i)/float64(sampleRate)))
+		taps[i] = complex64(complex128(lp[i]) * shift)
+	}
+	return taps
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,67 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import "testing"
+
+// TestLowpassKaiserUsesBeta guards against Lowpass silently designing a
+// rectangular window for window == Kaiser (beta == 0 makes besselI0(0) /
+// besselI0(0) == 1 for every tap), which guts the requested stopband
+// attenuation.
+func TestLowpassKaiserUsesBeta(t *testing.T) {
+	const (
+		numTaps    = 51
+		cutoff     = 1000
+		sampleRate = 8000
+	)
+
+	rect := Lowpass(numTaps, cutoff, sampleRate, Kaiser, 0)
+	tapered := Lowpass(numTaps, cutoff, sampleRate, Kaiser, 60)
+
+	mid := numTaps / 2
+	if rect[mid] == tapered[mid] {
+		t.Fatalf("Lowpass(..., Kaiser, 60) produced the same center tap as Lowpass(..., Kaiser, 0); window isn't varying with stopbandAttenuationDB")
+	}
+
+	// A real Kaiser taper should attenuate the edge taps relative to the
+	// center much more than a rectangular window does.
+	edgeRatio := real(tapered[0]) / real(tapered[mid])
+	rectRatio := real(rect[0]) / real(rect[mid])
+	if edgeRatio >= rectRatio {
+		t.Fatalf("tapered edge/center ratio %v not smaller than rectangular %v; Kaiser window isn't tapering", edgeRatio, rectRatio)
+	}
+}
+
+// TestKaiserBetaIncreasesWithAttenuation checks the monotonic relationship
+// KaiserBeta relies on callers assuming: a deeper requested stopband
+// produces a larger (wider mainlobe, better rejection) Beta.
+func TestKaiserBetaIncreasesWithAttenuation(t *testing.T) {
+	last := KaiserBeta(21)
+	for _, db := range []float64{30, 40, 50, 60, 80} {
+		beta := KaiserBeta(db)
+		if beta <= last {
+			t.Fatalf("KaiserBeta(%v) = %v, expected > KaiserBeta of a smaller attenuation (%v)", db, beta, last)
+		}
+		last = beta
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,103 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package filter implements FIR and IIR filter design and execution, as a
+// replacement for designing filters by stamping bins directly in the
+// frequency domain.
+package filter
+
+import "math"
+
+// Window is a windowing function used to taper windowed-sinc FIR designs,
+// trading mainlobe width for stopband attenuation.
+type Window int
+
+const (
+	// Hamming is a good general purpose window: modest stopband
+	// attenuation (~43 dB) with a fairly narrow transition.
+	Hamming Window = iota
+
+	// Blackman trades transition width for much better stopband
+	// attenuation (~58 dB).
+	Blackman
+
+	// Kaiser is parameterized by Beta, and can be tuned to hit a specific
+	// stopband attenuation for the narrowest possible transition band.
+	Kaiser
+)
+
+// apply returns the window coefficients for n taps. beta is only used by
+// the Kaiser window; pass KaiserBeta(stopbandAttenuationDB) for it.
+func (w Window) apply(n int, beta float64) []float64 {
+	taps := make([]float64, n)
+	m := float64(n - 1)
+
+	switch w {
+	case Blackman:
+		for i := range taps {
+			x := 2 * math.Pi * float64(i) / m
+			taps[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		}
+	case Kaiser:
+		denom := besselI0(beta)
+		for i := range taps {
+			r := 2*float64(i)/m - 1
+			taps[i] = besselI0(beta*math.Sqrt(1-r*r)) / denom
+		}
+	case Hamming:
+		fallthrough
+	default:
+		for i := range taps {
+			taps[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/m)
+		}
+	}
+	return taps
+}
+
+// KaiserBeta computes the Kaiser window Beta shape parameter for a desired
+// stopband attenuation, in dB, using Kaiser's standard approximation.
+func KaiserBeta(stopbandAttenuationDB float64) float64 {
+	a := stopbandAttenuationDB
+	switch {
+	case a > 50:
+		return 0.1102 * (a - 8.7)
+	case a >= 21:
+		return 0.5842*math.Pow(a-21, 0.4) + 0.07886*(a-21)
+	default:
+		return 0
+	}
+}
+
+// besselI0 approximates the zeroth order modified Bessel function of the
+// first kind, used by the Kaiser window.
+func besselI0(x float64) float64 {
+	sum := 1.0
+	term := 1.0
+	for k := 1; k < 32; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,46 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package filter
+
+import "testing"
+
+func TestWindowsTaperTowardEdges(t *testing.T) {
+	const n = 33 // odd, so there's a well-defined center tap
+	mid := n / 2
+
+	for _, w := range []Window{Hamming, Blackman, Kaiser} {
+		taps := w.apply(n, KaiserBeta(60))
+		if taps[0] >= taps[mid] {
+			t.Errorf("window %v: edge tap %v >= center tap %v, expected tapering", w, taps[0], taps[mid])
+		}
+		if taps[0] != taps[n-1] {
+			t.Errorf("window %v: not symmetric, taps[0]=%v taps[n-1]=%v", w, taps[0], taps[n-1])
+		}
+	}
+}
+
+func TestBesselI0AtZero(t *testing.T) {
+	if got := besselI0(0); got != 1 {
+		t.Errorf("besselI0(0) = %v, want 1", got)
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,25 @@
+package internal
+
+import "math"
+
+// OnePoleLowpass is a single-pole IIR lowpass, y[n] = a*x[n] + (1-a)*y[n-1].
+// It's used anywhere a cheap smoothing filter is good enough: pilot-tone
+// envelope/error smoothing, squelch averaging, and the like.
+type OnePoleLowpass struct {
+	a     float64
+	state float64
+}
+
+// NewOnePoleLowpass creates a OnePoleLowpass with a -3dB cutoff of cutoffHz,
+// operating at sampleRate samples/sec.
+func NewOnePoleLowpass(cutoffHz float64, sampleRate float64) *OnePoleLowpass {
+	rc := 1 / (2 * math.Pi * cutoffHz)
+	dt := 1 / sampleRate
+	return &OnePoleLowpass{a: dt / (rc + dt)}
+}
+
+// Step filters a single sample and returns the new output.
+func (f *OnePoleLowpass) Step(x float64) float64 {
+	f.state += f.a * (x - f.state)
+	return f.state
+}
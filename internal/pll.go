@@ -0,0 +1,74 @@
+package internal
+
+import "math"
+
+// PLL is a simple second-order phase-locked loop, driven one sample at a
+// time by a phase (or phase-error) detector. It's used to track the 19 kHz
+// stereo pilot, the RDS subcarrier, and the quadrature FM discriminator.
+//
+// The loop is the standard NCO + proportional/integral loop filter form:
+// the phase error feeds a PI filter, whose output is the instantaneous
+// frequency of the NCO, which is integrated to produce the NCO phase.
+type PLL struct {
+	// SampleRate is the rate, in Hz, that Step is called at.
+	SampleRate float64
+
+	// phase and freq are the NCO's state, in radians and radians/sample.
+	phase float64
+	freq  float64
+
+	// centerFreq is the free-running (no error) NCO frequency, in
+	// radians/sample.
+	centerFreq float64
+
+	// alpha and beta are the proportional and integral gains of the loop
+	// filter, derived from the loop bandwidth and damping factor.
+	alpha float64
+	beta  float64
+}
+
+// NewPLL creates a PLL centered at centerFreq Hz, with a loop bandwidth
+// loopBW (Hz) and the given dampingFactor (dimensionless, ~0.707 is a
+// reasonable default for a critically damped loop).
+func NewPLL(sampleRate float64, centerFreq float64, loopBW float64, dampingFactor float64) *PLL {
+	var (
+		theta = loopBW * 2 * math.Pi / sampleRate / (dampingFactor + 1/(4*dampingFactor))
+		d     = 1 + 2*dampingFactor*theta + theta*theta
+	)
+
+	p := &PLL{
+		SampleRate: sampleRate,
+		freq:       centerFreq * 2 * math.Pi / sampleRate,
+		centerFreq: centerFreq * 2 * math.Pi / sampleRate,
+		alpha:      (4 * dampingFactor * theta) / d,
+		beta:       (4 * theta * theta) / d,
+	}
+	return p
+}
+
+// Phase returns the current NCO phase, in radians.
+func (p *PLL) Phase() float64 {
+	return p.phase
+}
+
+// Freq returns the current NCO frequency, in radians/sample.
+func (p *PLL) Freq() float64 {
+	return p.freq
+}
+
+// Step advances the NCO by one sample given a phase error (in radians,
+// positive meaning the NCO is lagging), and returns the new NCO phase.
+func (p *PLL) Step(phaseError float64) float64 {
+	p.freq += p.beta * phaseError
+	p.phase += p.freq + p.alpha*phaseError
+	p.phase = math.Mod(p.phase, 2*math.Pi)
+	return p.phase
+}
+
+// FreqError returns how far the current NCO frequency has wandered from
+// the center frequency the loop was created with, in radians/sample. This
+// is a useful lock-detector input: a loop that's tracking a real tone will
+// sit close to its center frequency, while an unlocked loop wanders.
+func (p *PLL) FreqError() float64 {
+	return p.freq - p.centerFreq
+}
@@ -44,12 +44,13 @@ type ModulatorConfig struct {
 	// Audio data.
 	IqBufferLength uint
 
-	// IqSamplesPerAudioSample controls how many Iq samples need to be generated
-	// for each Audio sample that comes in.
-	//
-	// If the input AudioSampleRate is 44,100, and the IqSamplesPerAudioSample is
-	// 10, the output SampleRate of the sdr.Reader will be 441,000.
-	IqSamplesPerAudioSample uint
+	// IqSampleRate is the number of IQ samples per second to generate.
+	// Unlike the old IqSamplesPerAudioSample field, this doesn't need to
+	// be an integer multiple of AudioSampleRate: the number of IQ ticks
+	// generated per audio sample is varied, using a simple accumulator, so
+	// that it averages out to exactly IqSampleRate/AudioSampleRate over
+	// time.
+	IqSampleRate rf.Hz
 
 	// CarrierFrequency controls the frequency of the carrier that will be
 	// modulated by incoming data.
@@ -65,6 +66,12 @@ type ModulatorConfig struct {
 	// Dest is where to send IQ samples to as audio data is written to the
 	// Modulator.
 	Dest sdr.Writer
+
+	// Preemphasis is the pre-emphasis time constant to apply to incoming
+	// audio before modulation. Use Deemphasis75us or Deemphasis50us to
+	// match the de-emphasis the receiver will apply, or leave this at 0 to
+	// disable pre-emphasis entirely.
+	Preemphasis Deemphasis
 }
 
 // NewModulator allocates
@@ -79,12 +86,12 @@ func NewModulator(cfg ModulatorConfig) (*Modulator, error) {
 	//
 	// - check that the sample format is complex64.
 
-	iqSampleRate := cfg.AudioSampleRate * cfg.IqSamplesPerAudioSample
-
 	return &Modulator{
 		Config:       cfg,
-		iqSampleRate: uint(iqSampleRate),
+		iqSampleRate: uint(cfg.IqSampleRate),
+		iqPerAudio:   float64(cfg.IqSampleRate) / float64(cfg.AudioSampleRate),
 		iqBuffer:     make(sdr.SamplesC64, cfg.IqBufferLength),
+		preemph:      newPreemphasisFilter(cfg.Preemphasis, float64(cfg.AudioSampleRate)),
 	}, nil
 }
 
@@ -96,6 +103,15 @@ type Modulator struct {
 	// iqSampleRate is the final samples per second of the samples written
 	iqSampleRate uint
 
+	// iqPerAudio is the (possibly non-integer) number of IQ samples
+	// generated per audio sample, IqSampleRate/AudioSampleRate.
+	iqPerAudio float64
+
+	// iqAccum accumulates the fractional part of iqPerAudio between Write
+	// calls, so the number of IQ ticks generated per audio sample can be
+	// varied to track a non-integer iqPerAudio exactly, on average.
+	iqAccum float64
+
 	// iqBuffer will be used when generating data to send to the Writer
 	iqBuffer sdr.SamplesC64
 
@@ -103,6 +119,9 @@ type Modulator struct {
 	// of samples per second will return how many seconds of data have been
 	// processed.
 	timeOffset uint
+
+	// preemph applies pre-emphasis to incoming audio, if configured.
+	preemph *preemphasisFilter
 }
 
 // SampleRate implements the sdr.Writer interface.
@@ -114,18 +133,21 @@ func (m *Modulator) SampleRate() uint {
 // Sample Rate, modulate them against the Carrier using Frequency Modulation,
 // and write the IQ data to the resulting sdr.Writer
 func (m *Modulator) Write(audioSamples []float32) (int, error) {
-	iqBufLen := len(m.iqBuffer) / int(m.Config.IqSamplesPerAudioSample)
+	// Worst case, every audio sample in a chunk generates ceil(iqPerAudio)
+	// IQ ticks; size chunks so that can never overflow m.iqBuffer.
+	maxIqPerAudio := int(math.Ceil(m.iqPerAudio)) + 1
+	audioBufLen := len(m.iqBuffer) / maxIqPerAudio
 
 	var fn int
-	for i := 0; i < len(audioSamples); i += iqBufLen {
-		audioEnd := i + iqBufLen
+	for i := 0; i < len(audioSamples); i += audioBufLen {
+		audioEnd := i + audioBufLen
 		if audioEnd > len(audioSamples) {
 			audioEnd = len(audioSamples)
 		}
 
 		n, err := m.write(audioSamples[i:audioEnd])
 		if err != nil {
-			return n, err
+			return fn, err
 		}
 		fn += n
 
@@ -138,51 +160,48 @@ func (m *Modulator) Write(audioSamples []float32) (int, error) {
 
 // perform the actual write
 func (m *Modulator) write(audioSamples []float32) (int, error) {
-	iqPerA := int(m.Config.IqSamplesPerAudioSample)
-
-	if len(m.iqBuffer) < len(audioSamples)*iqPerA {
-		return 0, fmt.Errorf("fmtx.Write: iq buffer is too short for audio buffer")
-	}
-
 	timeOffset := float64(m.timeOffset)
 	beta := m.Config.Beta
 
+	iqStep := 0
 	for audioStep := range audioSamples {
-		var (
-			audioSample = float64(audioSamples[audioStep])
-			iqStepStart = audioStep * iqPerA
-			iqStepEnd   = iqStepStart + iqPerA
-		)
+		sample := audioSamples[audioStep]
+		if m.preemph != nil {
+			sample = m.preemph.Step(sample)
+		}
+		audioSample := float64(sample)
+
+		m.iqAccum += m.iqPerAudio
+		ticks := int(m.iqAccum)
+		m.iqAccum -= float64(ticks)
+
+		if iqStep+ticks > len(m.iqBuffer) {
+			return 0, fmt.Errorf("fmtx.Write: iq buffer is too short for audio buffer")
+		}
 
-		for iqStep := iqStepStart; iqStep < iqStepEnd; iqStep++ {
+		for ; ticks > 0; ticks-- {
 			var (
 				now        = timeOffset / float64(m.iqSampleRate)
 				realSample = math.Cos(tau*float64(m.Config.CarrierFrequency)*now + beta*audioSample)
 				imagSample = math.Sin(tau*float64(m.Config.CarrierFrequency)*now + beta*audioSample)
 			)
 			m.iqBuffer[iqStep] = complex(float32(realSample), float32(imagSample))
-			timeOffset = (timeOffset + 1)
+			iqStep++
+			timeOffset++
 		}
 	}
 
-	expectedSamples := len(audioSamples) * int(iqPerA)
-
-	n, err := m.Config.Dest.Write(m.iqBuffer[:expectedSamples])
+	n, err := m.Config.Dest.Write(m.iqBuffer[:iqStep])
 	if err != nil {
-		return n / iqPerA, err
-	}
-
-	if n != expectedSamples {
-		return n / iqPerA, fmt.Errorf("fmtx.Write: i wrote a bad count, %d vs %d", n, expectedSamples)
+		return 0, err
 	}
 
-	timeTicks := uint(timeOffset) - m.timeOffset
-	if timeTicks != uint(expectedSamples) {
-		return n / iqPerA, fmt.Errorf("fmtx.Write: timeTick mismatch %d vs %d", timeTicks, expectedSamples)
+	if n != iqStep {
+		return 0, fmt.Errorf("fmtx.Write: i wrote a bad count, %d vs %d", n, iqStep)
 	}
 
 	m.timeOffset = uint(timeOffset)
-	return expectedSamples / iqPerA, err
+	return len(audioSamples), nil
 }
 
 // vim: foldmethod=marker
@@ -0,0 +1,78 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package rds decodes the Radio Data System subcarrier carried on the FM
+// broadcast multiplex, alongside the stereo pilot.
+package rds
+
+// BlockOffset identifies which of the four 26-bit blocks in an RDS group
+// a 16-bit payload plus 10-bit checkword came from.
+type BlockOffset int
+
+const (
+	// OffsetA marks the first block of a group (always the PI code).
+	OffsetA BlockOffset = iota
+	// OffsetB marks the second block (group type, version, and flags).
+	OffsetB
+	// OffsetC marks the third block.
+	OffsetC
+	// OffsetCPrime marks the alternate third-block offset word used by
+	// version B groups.
+	OffsetCPrime
+	// OffsetD marks the fourth block.
+	OffsetD
+)
+
+// offsetWords are the 10-bit words XORed into the checkword of each block,
+// per the RDS standard (IEC 62106).
+var offsetWords = map[BlockOffset]uint16{
+	OffsetA:      0x0FC,
+	OffsetB:      0x198,
+	OffsetC:      0x168,
+	OffsetCPrime: 0x350,
+	OffsetD:      0x1B4,
+}
+
+// genPoly is the RDS (26,16) cyclic code generator polynomial,
+// x^10+x^8+x^7+x^5+x^4+x^3+1, represented as its 11-bit coefficient
+// vector (MSB first, degree 10 down to 0).
+const genPoly = 0x5B9
+
+// syndrome computes the 10-bit syndrome of a 26-bit block (16 data bits
+// followed by a 10-bit checkword, MSB first) under genPoly, by binary
+// polynomial long division.
+func syndrome(block uint32) uint16 {
+	reg := block
+	for bit := 25; bit >= 10; bit-- {
+		if reg&(1<<uint(bit)) != 0 {
+			reg ^= genPoly << uint(bit-10)
+		}
+	}
+	return uint16(reg & 0x3FF)
+}
+
+// checkOffset reports whether block (26 bits: 16 data bits + 10 check
+// bits) is a valid codeword for the given offset, i.e. its syndrome
+// matches the offset word.
+func checkOffset(block uint32, offset BlockOffset) bool {
+	return syndrome(block) == offsetWords[offset]
+}
+
+// vim: foldmethod=marker
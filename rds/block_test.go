@@ -0,0 +1,67 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+import "testing"
+
+// encodeBlock builds a valid 26-bit codeword (16 data bits + 10 check
+// bits) for offset, given the syndrome computation is GF(2)-linear and a
+// checkword's own syndrome is itself (since it only occupies the low 10
+// bits, which the division loop never touches on its own).
+func encodeBlock(data uint16, offset BlockOffset) uint32 {
+	check := syndrome(uint32(data)<<10) ^ offsetWords[offset]
+	return uint32(data)<<10 | uint32(check)
+}
+
+func TestCheckOffsetValidCodeword(t *testing.T) {
+	for offset, want := range offsetWords {
+		block := encodeBlock(0xBEEF, offset)
+		if !checkOffset(block, offset) {
+			t.Errorf("checkOffset(encodeBlock(0xBEEF, %v), %v) = false, want true", offset, offset)
+		}
+		if got := syndrome(block); got != want {
+			t.Errorf("syndrome(encodeBlock(0xBEEF, %v)) = %#x, want %#x", offset, got, want)
+		}
+	}
+}
+
+func TestCheckOffsetRejectsCorruption(t *testing.T) {
+	block := encodeBlock(0x1234, OffsetA)
+
+	for bit := uint(0); bit < blockBits; bit++ {
+		corrupted := block ^ (1 << bit)
+		if checkOffset(corrupted, OffsetA) {
+			t.Errorf("checkOffset passed a codeword with bit %d flipped", bit)
+		}
+	}
+}
+
+func TestCheckOffsetRejectsWrongOffset(t *testing.T) {
+	block := encodeBlock(0x1234, OffsetA)
+
+	for _, offset := range []BlockOffset{OffsetB, OffsetC, OffsetCPrime, OffsetD} {
+		if checkOffset(block, offset) {
+			t.Errorf("checkOffset(block encoded for OffsetA, %v) = true, want false", offset)
+		}
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,165 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+import (
+	"math"
+
+	"hz.tools/fm/filter"
+	"hz.tools/fm/internal"
+)
+
+// SymbolRate is the RDS biphase symbol rate, in baud.
+const SymbolRate = 1187.5
+
+// SubcarrierFrequency is the RDS subcarrier frequency: the third harmonic
+// of the 19 KHz stereo pilot.
+const SubcarrierFrequency = 3 * 19000
+
+// DefaultRequiredGoodBlocks is a reasonable default for Sync.RequiredGood:
+// enough to all but rule out a false lock on noise.
+const DefaultRequiredGoodBlocks = 4
+
+// Decoder demodulates one sample at a time from the FM multiplex baseband
+// down to RDS Groups: band-pass around 57 KHz, coherently downconvert
+// (locked to 3x the stereo pilot phase when available, or a free-running
+// NCO otherwise), recover symbol timing with a Gardner timing-error
+// detector, differentially decode, and hand bits to a Sync.
+type Decoder struct {
+	sampleRate float64
+
+	bp  *filter.Biquad
+	lpf *filter.Biquad
+
+	// freeNCO phase is used to coherently downconvert when no pilot phase
+	// is supplied to Step (e.g. a mono station, or a Demodulator not
+	// running stereo decode).
+	freeNCOPhase float64
+	freeNCOStep  float64
+
+	samplesPerSymbol float64
+	// symbolPhase counts samples since the last symbol decision, in the
+	// range [0, samplesPerSymbol).
+	symbolPhase float64
+
+	lastSample  float64
+	midSample   float64
+	haveMid     bool
+	lastBitVal  byte
+	havePrevBit bool
+
+	sync *Sync
+
+	// OnBlock, if set, is called every time four consecutive blocks check
+	// out; see Sync for what "check out" means.
+	OnBlock func(Block, bool)
+}
+
+// NewDecoder creates a Decoder operating on MPX samples at sampleRate.
+func NewDecoder(sampleRate float64) *Decoder {
+	d := &Decoder{
+		sampleRate:       sampleRate,
+		bp:               filter.BPF(SubcarrierFrequency, sampleRate, 12),
+		lpf:              filter.LPF(SymbolRate, sampleRate, 0.707),
+		freeNCOStep:      2 * math.Pi * SubcarrierFrequency / sampleRate,
+		samplesPerSymbol: sampleRate / SymbolRate,
+		sync:             NewSync(DefaultRequiredGoodBlocks),
+	}
+	return d
+}
+
+// Locked reports whether the block synchronizer is currently locked.
+func (d *Decoder) Locked() bool {
+	return d.sync.Locked()
+}
+
+// Step processes one MPX sample. pilotPhase is 1x the stereo pilot PLL's
+// phase (in radians) from a StereoDemodulator; pass pilotOK=false (e.g. for a
+// mono station, or when the pilot isn't locked) to fall back to the
+// free-running NCO.
+func (d *Decoder) Step(mpx float64, pilotPhase float64, pilotOK bool) {
+	filtered := d.bp.Step(mpx)
+
+	var carrierPhase float64
+	if pilotOK {
+		carrierPhase = 3 * pilotPhase
+	} else {
+		d.freeNCOPhase += d.freeNCOStep
+		carrierPhase = d.freeNCOPhase
+	}
+
+	baseband := filtered * math.Cos(carrierPhase)
+	sample := d.lpf.Step(baseband)
+
+	d.symbolPhase++
+
+	half := d.samplesPerSymbol / 2
+	if !d.haveMid && d.symbolPhase >= half {
+		d.midSample = sample
+		d.haveMid = true
+	}
+
+	if d.symbolPhase < d.samplesPerSymbol {
+		d.lastSample = sample
+		return
+	}
+
+	// Gardner timing-error detector: e = (current - previous) * mid.
+	// Nudging symbolPhase by the sign of e tracks the true symbol clock
+	// without needing a separate NCO for the data rate.
+	err := (sample - d.lastSample) * d.midSample
+	const gain = 0.01
+	d.symbolPhase -= d.samplesPerSymbol - (half * gain * sign(err))
+
+	d.haveMid = false
+	d.lastSample = sample
+
+	// A biphase (Manchester) symbol's sign is the sign of the first-half
+	// vs second-half energy; since we've already lowpass-filtered to
+	// roughly the symbol rate, the instantaneous sign of the decision
+	// sample is a good enough proxy.
+	symbol := sample >= 0
+
+	var bitVal byte
+	if symbol {
+		bitVal = 1
+	}
+
+	// RDS bits are differentially encoded: a transmitted 1 is a change in
+	// biphase symbol polarity, a 0 is no change.
+	if d.havePrevBit {
+		bit := bitVal ^ d.lastBitVal
+		if block, ok := d.sync.Push(bit); ok && d.OnBlock != nil {
+			d.OnBlock(block, d.sync.Locked())
+		}
+	}
+	d.lastBitVal = bitVal
+	d.havePrevBit = true
+}
+
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+// vim: foldmethod=marker
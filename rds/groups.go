@@ -0,0 +1,113 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+// Block is the four raw 16-bit data words of one RDS group, after error
+// checking has stripped the checkwords.
+type Block [4]uint16
+
+// PI returns the Program Identification code, carried in block A of every
+// group.
+func (b Block) PI() uint16 {
+	return b[0]
+}
+
+// GroupType returns the group number (0-15) and version (false for A,
+// true for B), carried in block B.
+func (b Block) GroupType() (number int, versionB bool) {
+	return int(b[1] >> 12), b[1]&(1<<11) != 0
+}
+
+// TrafficProgram reports the TP flag, carried in block B.
+func (b Block) TrafficProgram() bool {
+	return b[1]&(1<<10) != 0
+}
+
+// PTY returns the Program Type code, carried in block B.
+func (b Block) PTY() int {
+	return int((b[1] >> 5) & 0x1F)
+}
+
+// Group is implemented by every typed group below, so a Tuner can accept
+// whichever ones it knows how to fold into a Station.
+type Group interface {
+	// PI returns the Program Identification code for this group.
+	PI() uint16
+}
+
+// Group0A carries Program Service name characters and AF (alternative
+// frequency) codes.
+type Group0A struct {
+	Block
+
+	// Segment is which 2-character segment of the 8-character PS name
+	// this group carries (0-3).
+	Segment int
+
+	// Chars holds the two PS name characters for Segment.
+	Chars [2]byte
+
+	// AF holds up to two alternative-frequency codes from block C.
+	AF [2]byte
+}
+
+// ParseGroup0A parses a Group0A from a type-0 block.
+func ParseGroup0A(b Block) Group0A {
+	return Group0A{
+		Block:   b,
+		Segment: int(b[1] & 0x3),
+		Chars:   [2]byte{byte(b[3] >> 8), byte(b[3])},
+		AF:      [2]byte{byte(b[2] >> 8), byte(b[2])},
+	}
+}
+
+// Group2A carries RadioText characters, 4 per group, in one of 16
+// segments (up to 64 characters of RadioText total).
+type Group2A struct {
+	Block
+
+	// Segment is which 4-character segment of RadioText this group
+	// carries (0-15).
+	Segment int
+
+	// TextReset toggles every time the station starts a new RadioText
+	// message; a change from the previously seen value means a Tuner
+	// should clear its buffered RadioText.
+	TextReset bool
+
+	// Chars holds the four RadioText characters for Segment.
+	Chars [4]byte
+}
+
+// ParseGroup2A parses a Group2A from a type-2, version A block.
+func ParseGroup2A(b Block) Group2A {
+	return Group2A{
+		Block:     b,
+		Segment:   int(b[1] & 0xF),
+		TextReset: b[1]&(1<<4) != 0,
+		Chars: [4]byte{
+			byte(b[2] >> 8), byte(b[2]),
+			byte(b[3] >> 8), byte(b[3]),
+		},
+	}
+}
+
+// vim: foldmethod=marker
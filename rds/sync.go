@@ -0,0 +1,147 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+// blockBits is the width of one RDS block: 16 data bits plus a 10-bit
+// checkword.
+const blockBits = 26
+
+// Sync finds and tracks RDS block boundaries in a differentially-decoded
+// bitstream, using the offset-word syndrome check defined in block.go.
+//
+// Acquisition starts as soon as a single block matches offset A; from
+// there, Sync requires RequiredGood consecutive in-sequence blocks (A, B,
+// C or C', D, A, ...) before it reports itself Locked. A single bad block
+// once locked drops sync immediately, since a real misalignment will fail
+// every subsequent block too.
+type Sync struct {
+	// RequiredGood is the number of consecutive good blocks needed before
+	// Locked returns true.
+	RequiredGood int
+
+	reg      uint32
+	bitCount int
+
+	acquired bool
+	expect   BlockOffset
+
+	consecutiveGood int
+
+	data    Block
+	dataIdx int
+}
+
+// NewSync creates a Sync requiring requiredGood consecutive good blocks
+// before declaring lock.
+func NewSync(requiredGood int) *Sync {
+	if requiredGood < 1 {
+		requiredGood = 1
+	}
+	return &Sync{RequiredGood: requiredGood, expect: OffsetA}
+}
+
+// Locked reports whether Sync has seen enough consecutive good blocks to
+// trust the block boundaries it's reporting.
+func (s *Sync) Locked() bool {
+	return s.acquired && s.consecutiveGood >= s.RequiredGood
+}
+
+// Push feeds one recovered bit into the synchronizer. It returns a
+// completed Block and true once blocks A through D have all checked out
+// in sequence.
+func (s *Sync) Push(bit byte) (Block, bool) {
+	s.reg = ((s.reg << 1) | uint32(bit&1)) & (1<<blockBits - 1)
+	s.bitCount++
+
+	if !s.acquired {
+		if checkOffset(s.reg, OffsetA) {
+			s.acquire(OffsetA)
+		}
+		return Block{}, false
+	}
+
+	if s.bitCount < blockBits {
+		return Block{}, false
+	}
+	s.bitCount = 0
+
+	if !s.blockMatches(s.expect) {
+		s.lose()
+		// The bit we just pushed might itself be a fresh A; don't drop
+		// it on the floor.
+		if checkOffset(s.reg, OffsetA) {
+			s.acquire(OffsetA)
+		}
+		return Block{}, false
+	}
+
+	s.data[s.dataIdx] = uint16(s.reg >> 10)
+	s.dataIdx++
+	s.consecutiveGood++
+
+	if s.expect != OffsetD {
+		s.expect = nextOffset(s.expect)
+		return Block{}, false
+	}
+
+	block := s.data
+	s.expect = OffsetA
+	s.dataIdx = 0
+	return block, true
+}
+
+// nextOffset returns the offset expected after offset, treating C and C'
+// as interchangeable (both are followed by D).
+func nextOffset(offset BlockOffset) BlockOffset {
+	switch offset {
+	case OffsetA:
+		return OffsetB
+	case OffsetB:
+		return OffsetC
+	default: // OffsetC, OffsetCPrime
+		return OffsetD
+	}
+}
+
+func (s *Sync) blockMatches(offset BlockOffset) bool {
+	if offset == OffsetC {
+		return checkOffset(s.reg, OffsetC) || checkOffset(s.reg, OffsetCPrime)
+	}
+	return checkOffset(s.reg, offset)
+}
+
+func (s *Sync) acquire(offset BlockOffset) {
+	s.acquired = true
+	s.bitCount = 0
+	s.expect = nextOffset(offset)
+	s.consecutiveGood = 1
+	s.data[0] = uint16(s.reg >> 10)
+	s.dataIdx = 1
+}
+
+func (s *Sync) lose() {
+	s.acquired = false
+	s.consecutiveGood = 0
+	s.dataIdx = 0
+	s.expect = OffsetA
+}
+
+// vim: foldmethod=marker
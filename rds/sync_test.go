@@ -0,0 +1,86 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+import "testing"
+
+// pushBlock feeds one 26-bit codeword into s, MSB first, returning
+// whatever the final bit's Push call returns.
+func pushBlock(s *Sync, block uint32) (Block, bool) {
+	var b Block
+	var ok bool
+	for i := blockBits - 1; i >= 0; i-- {
+		b, ok = s.Push(byte(block>>uint(i)) & 1)
+	}
+	return b, ok
+}
+
+func TestSyncLocksAndDecodesGroup(t *testing.T) {
+	s := NewSync(DefaultRequiredGoodBlocks)
+
+	data := [4]uint16{0x1001, 0x0203, 0x3344, 0x5566}
+	offsets := [4]BlockOffset{OffsetA, OffsetB, OffsetC, OffsetD}
+
+	var group Block
+	var complete bool
+	for round := 0; round < DefaultRequiredGoodBlocks; round++ {
+		for i, offset := range offsets {
+			group, complete = pushBlock(s, encodeBlock(data[i], offset))
+		}
+	}
+
+	if !complete {
+		t.Fatal("final group push didn't report complete")
+	}
+	if group != Block(data) {
+		t.Fatalf("decoded group = %#v, want %#v", group, Block(data))
+	}
+	if !s.Locked() {
+		t.Fatalf("Sync not Locked() after %d consecutive good groups", DefaultRequiredGoodBlocks)
+	}
+}
+
+func TestSyncDropsLockOnBadBlock(t *testing.T) {
+	s := NewSync(2)
+
+	data := [4]uint16{0x0001, 0x0002, 0x0003, 0x0004}
+	offsets := [4]BlockOffset{OffsetA, OffsetB, OffsetC, OffsetD}
+	for round := 0; round < 2; round++ {
+		for i, offset := range offsets {
+			pushBlock(s, encodeBlock(data[i], offset))
+		}
+	}
+	if !s.Locked() {
+		t.Fatal("expected Sync to be Locked after two good groups")
+	}
+
+	// Corrupt a single bit of an otherwise-valid B block; Sync should
+	// drop lock rather than silently accept a misaligned group.
+	bad := encodeBlock(data[1], OffsetB) ^ 1
+	pushBlock(s, encodeBlock(data[0], OffsetA))
+	pushBlock(s, bad)
+
+	if s.Locked() {
+		t.Fatal("Sync stayed Locked after a corrupted block")
+	}
+}
+
+// vim: foldmethod=marker
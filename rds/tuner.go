@@ -0,0 +1,192 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package rds
+
+// Station is a live snapshot of everything a Tuner has decoded so far
+// about the station it's tuned to.
+type Station struct {
+	// PI is the Program Identification code.
+	PI uint16
+
+	// PS is the 8-character Program Service (station) name. Segments
+	// that haven't been received yet are left as spaces.
+	PS string
+
+	// PTY is the Program Type code, from the most recently seen group.
+	PTY int
+
+	// TP reports whether the station carries traffic announcements.
+	TP bool
+
+	// RadioText is the most recently assembled RadioText message, up to
+	// 64 characters.
+	RadioText string
+
+	// AlternateFrequencies lists the distinct alternative frequencies, in
+	// Hz, seen so far in Group0A blocks, sorted ascending. AF codes
+	// outside 1-204 (filler and the extended-list control codes) aren't
+	// single frequencies and are ignored.
+	AlternateFrequencies []uint32
+}
+
+// afBase and afStep decode an RDS AF code (1-204) to a frequency in Hz:
+// 87.5 MHz plus code increments of 100 KHz, per IEC 62106.
+const (
+	afBase uint32 = 87500000
+	afStep uint32 = 100000
+)
+
+// Tuner aggregates decoded RDS Groups into a live Station snapshot. Every
+// Group it successfully parses is also sent to Groups, for callers who
+// want the raw stream.
+type Tuner struct {
+	// Groups receives every successfully parsed Group. It's buffered;
+	// callers that don't drain it promptly will simply miss groups sent
+	// while it's full, rather than blocking decode.
+	Groups chan Group
+
+	station Station
+
+	psChars  [4][2]byte
+	psSeen   [4]bool
+	rtChars  [16][4]byte
+	rtSeen   [16]bool
+	rtABFlag bool
+	haveFlag bool
+
+	afSeen [205]bool // index is the AF code, 1-204 valid
+}
+
+// NewTuner creates an empty Tuner.
+func NewTuner() *Tuner {
+	return &Tuner{
+		Groups: make(chan Group, 16),
+		station: Station{
+			PS: "        ",
+		},
+	}
+}
+
+// Station returns the current snapshot. There's no internal locking, so
+// calling Station concurrently with Push is a data race.
+func (t *Tuner) Station() Station {
+	return t.station
+}
+
+// Push decodes one synchronized Block and folds it into the Station
+// snapshot, emitting a typed Group on Groups if the group type is one
+// Tuner understands.
+func (t *Tuner) Push(b Block) {
+	t.station.PI = b.PI()
+	t.station.PTY = b.PTY()
+	t.station.TP = b.TrafficProgram()
+
+	number, versionB := b.GroupType()
+
+	switch {
+	case number == 0 && !versionB:
+		g := ParseGroup0A(b)
+		t.psChars[g.Segment] = g.Chars
+		t.psSeen[g.Segment] = true
+		t.rebuildPS()
+		for _, code := range g.AF {
+			if code >= 1 && code <= 204 {
+				t.afSeen[code] = true
+			}
+		}
+		t.rebuildAF()
+		t.send(g)
+
+	case number == 2 && !versionB:
+		g := ParseGroup2A(b)
+		if t.haveFlag && g.TextReset != t.rtABFlag {
+			t.rtSeen = [16]bool{}
+		}
+		t.rtABFlag = g.TextReset
+		t.haveFlag = true
+		t.rtChars[g.Segment] = g.Chars
+		t.rtSeen[g.Segment] = true
+		t.rebuildRT()
+		t.send(g)
+	}
+}
+
+func (t *Tuner) rebuildPS() {
+	var ps [8]byte
+	for i := range ps {
+		ps[i] = ' '
+	}
+	for seg, ok := range t.psSeen {
+		if !ok {
+			continue
+		}
+		ps[seg*2] = t.psChars[seg][0]
+		ps[seg*2+1] = t.psChars[seg][1]
+	}
+	t.station.PS = string(ps[:])
+}
+
+func (t *Tuner) rebuildRT() {
+	var rt [64]byte
+	for i := range rt {
+		rt[i] = ' '
+	}
+	end := 0
+	for seg, ok := range t.rtSeen {
+		if !ok {
+			continue
+		}
+		for i, c := range t.rtChars[seg] {
+			idx := seg*4 + i
+			if c == 0x0D { // RadioText end-of-message marker
+				if idx > end {
+					end = idx
+				}
+				continue
+			}
+			rt[idx] = c
+			if idx+1 > end {
+				end = idx + 1
+			}
+		}
+	}
+	t.station.RadioText = string(rt[:end])
+}
+
+func (t *Tuner) rebuildAF() {
+	var freqs []uint32
+	for code, seen := range t.afSeen {
+		if !seen {
+			continue
+		}
+		freqs = append(freqs, afBase+uint32(code)*afStep)
+	}
+	t.station.AlternateFrequencies = freqs
+}
+
+func (t *Tuner) send(g Group) {
+	select {
+	case t.Groups <- g:
+	default:
+	}
+}
+
+// vim: foldmethod=marker
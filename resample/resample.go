@@ -0,0 +1,199 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package resample implements a polyphase rational resampler, so IQ or
+// audio streams can be retimed between sample rates that don't divide
+// cleanly (e.g. 2.048 Msps down to 48 KHz, an L/M of 375/16000).
+package resample
+
+import (
+	"fmt"
+
+	"hz.tools/fm/filter"
+	"hz.tools/rf"
+	"hz.tools/sdr"
+)
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Reader wraps an sdr.Reader, resampling it to a new rate by an arbitrary
+// rational factor L/M: the input is conceptually upsampled by L (zero
+// stuffing), lowpass filtered, and downsampled by M, but run in polyphase
+// commutator form so only numTaps/L multiply-adds happen per output
+// sample, rather than filtering the (mostly zero) upsampled stream
+// directly.
+type Reader struct {
+	reader  sdr.Reader
+	outRate uint
+
+	l, m int
+
+	taps          [][]complex64 // l polyphase branches
+	tapsPerBranch int
+
+	// buffer holds input history: the last tapsPerBranch-1 samples
+	// consumed, immediately followed by any freshly read samples, are
+	// kept here between Read calls.
+	buffer []complex64
+	// pos is the current commutator position, in units of 1/l of an
+	// input sample, relative to buffer[0].
+	pos int
+}
+
+// NewReader creates a Reader that resamples r to outRate.
+func NewReader(r sdr.Reader, outRate rf.Hz) (*Reader, error) {
+	if r.SampleFormat() != sdr.SampleFormatC64 {
+		return nil, sdr.ErrSampleFormatMismatch
+	}
+
+	inRate := int(r.SampleRate())
+	out := int(outRate)
+	if inRate <= 0 || out <= 0 {
+		return nil, fmt.Errorf("resample: invalid sample rate")
+	}
+
+	g := gcd(inRate, out)
+	l, m := out/g, inRate/g
+
+	// Design the prototype lowpass against the upsampled (by l) rate, with
+	// a cutoff at the lower of the two Nyquist rates so we reject both
+	// upsampling images and downsampling aliases.
+	upRate := uint(inRate * l)
+	nyquist := inRate
+	if out < nyquist {
+		nyquist = out
+	}
+	cutoff := rf.Hz(nyquist / 2)
+
+	numTaps := filter.Order(upRate, cutoff/10, 60)
+	// Round up to a multiple of l so the prototype divides evenly into l
+	// polyphase branches of equal length.
+	if rem := numTaps % l; rem != 0 {
+		numTaps += l - rem
+	}
+
+	proto := filter.Lowpass(numTaps, cutoff, upRate, filter.Kaiser, 60)
+	tapsPerBranch := numTaps / l
+
+	// Polyphase decomposition: branch p holds every l'th tap, scaled by l
+	// to restore the unity DC gain lost to zero-stuffing.
+	taps := make([][]complex64, l)
+	for p := 0; p < l; p++ {
+		branch := make([]complex64, tapsPerBranch)
+		for k := 0; k < tapsPerBranch; k++ {
+			branch[k] = proto[k*l+p] * complex(float32(l), 0)
+		}
+		taps[p] = branch
+	}
+
+	return &Reader{
+		reader:        r,
+		outRate:       uint(out),
+		l:             l,
+		m:             m,
+		taps:          taps,
+		tapsPerBranch: tapsPerBranch,
+		buffer:        make([]complex64, tapsPerBranch-1),
+		pos:           (tapsPerBranch - 1) * l,
+	}, nil
+}
+
+// SampleRate implements the sdr.Reader interface.
+func (r *Reader) SampleRate() uint {
+	return r.outRate
+}
+
+// SampleFormat implements the sdr.Reader interface.
+func (r *Reader) SampleFormat() sdr.SampleFormat {
+	return sdr.SampleFormatC64
+}
+
+// Close implements the sdr.Reader interface.
+func (r *Reader) Close() error {
+	return r.reader.Close()
+}
+
+// Read implements the sdr.Reader interface.
+func (r *Reader) Read(iqBuf sdr.Samples) (int, error) {
+	out, ok := iqBuf.(sdr.SamplesC64)
+	if !ok {
+		return 0, sdr.ErrSampleFormatMismatch
+	}
+
+	// Figure out how far into the input stream we'll need to read to
+	// produce len(out) output samples from our current commutator
+	// position, relative to buffer[0].
+	lastInputIdx := (r.pos + (len(out)-1)*r.m) / r.l
+	want := lastInputIdx - (len(r.buffer) - 1) + 1
+	if want < 0 {
+		want = 0
+	}
+
+	fresh := make(sdr.SamplesC64, want)
+	n, err := sdr.ReadFull(r.reader, fresh)
+	if err != nil && n == 0 {
+		return 0, err
+	}
+
+	buf := append(r.buffer, fresh[:n]...)
+
+	produced := 0
+	for produced < len(out) {
+		inputIdx := r.pos / r.l
+		phase := r.pos % r.l
+
+		taps := r.taps[phase]
+		if inputIdx-len(taps)+1 < 0 || inputIdx >= len(buf) {
+			break
+		}
+
+		var sum complex64
+		for k, tap := range taps {
+			sum += buf[inputIdx-k] * tap
+		}
+		out[produced] = sum
+		produced++
+		r.pos += r.m
+	}
+
+	// Carry the last tapsPerBranch-1 consumed samples forward as history
+	// for the next call, and rebase pos to match.
+	keep := r.tapsPerBranch - 1
+	dropped := r.pos/r.l - keep
+	if dropped > len(buf) {
+		dropped = len(buf)
+	}
+	if dropped > 0 {
+		r.pos -= dropped * r.l
+		r.buffer = append([]complex64{}, buf[dropped:]...)
+	} else {
+		r.buffer = buf
+	}
+
+	return produced, nil
+}
+
+// vim: foldmethod=marker
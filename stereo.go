@@ -0,0 +1,203 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2020
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package fm
+
+import (
+	"fmt"
+	"math"
+
+	"hz.tools/fm/filter"
+	"hz.tools/fm/internal"
+	"hz.tools/fm/rds"
+	"hz.tools/sdr"
+)
+
+// PilotFrequency is the frequency of the stereo pilot tone in the FM
+// broadcast multiplex signal, in Hz.
+const PilotFrequency = 19000
+
+// pilotLoopBW is the default loop bandwidth, in Hz, for the pilot PLL. This
+// is narrow enough to reject MPX energy outside the pilot while still
+// acquiring lock in well under a second.
+const pilotLoopBW = 9
+
+// pilotLockThreshold is how small the smoothed phase-detector error needs
+// to be (in radians) before the pilot is considered locked.
+const pilotLockThreshold = 0.05
+
+// StereoDemodulator recovers both the L+R (mono) and L-R (stereo
+// difference) signals from an FM broadcast multiplex (MPX) baseband, using
+// a PLL locked to the 19 kHz pilot tone to coherently demodulate the 38 kHz
+// DSB-SC subcarrier.
+type StereoDemodulator struct {
+	reader sdr.Reader
+	config DemodulatorConfig
+	disc   discriminatorState
+
+	lastPhasor complex128
+	havePhasor bool
+
+	mpxRate float64
+
+	pilotBP *filter.Biquad
+	pilot   *internal.PLL
+	lockLPF *internal.OnePoleLowpass
+	locked  bool
+
+	subBP *filter.Biquad
+
+	sumLPF  *internal.OnePoleLowpass
+	diffLPF *internal.OnePoleLowpass
+
+	deemphL *deemphasisFilter
+	deemphR *deemphasisFilter
+
+	rds *rds.Decoder
+}
+
+// PilotPhase returns the current phase, in radians, of the PLL tracking
+// the 19 KHz stereo pilot. This is the phase reference an rds.Decoder
+// needs to coherently downconvert the RDS subcarrier at 3x this frequency.
+func (s *StereoDemodulator) PilotPhase() float64 {
+	return s.pilot.Phase()
+}
+
+// EnableRDS creates an rds.Decoder wired to this StereoDemodulator's MPX
+// baseband and pilot PLL, and returns it so the caller can set OnBlock (or
+// wrap it in an rds.Tuner) before the next Read. Every subsequent Read
+// feeds it one MPX sample at a time automatically.
+func (s *StereoDemodulator) EnableRDS() *rds.Decoder {
+	s.rds = rds.NewDecoder(s.mpxRate)
+	return s.rds
+}
+
+// Locked returns true if the pilot PLL is currently tracking a pilot tone.
+// Callers should fall back to mono (L == R == MPX low-pass) when this is
+// false, since the recovered L-R signal is meaningless without a locked
+// pilot reference.
+func (s *StereoDemodulator) Locked() bool {
+	return s.locked
+}
+
+// SampleRate returns the audio sample rate, common to both the left and
+// right channels.
+func (s *StereoDemodulator) SampleRate() uint {
+	return uint(s.reader.SampleRate())
+}
+
+// Read fills left and right with demodulated stereo audio. Both slices
+// must be the same length. If the pilot is not locked, left and right will
+// both carry the mono (L+R) signal.
+func (s *StereoDemodulator) Read(left, right []float32) (int, error) {
+	if len(left) != len(right) {
+		return 0, fmt.Errorf("fm: left and right buffers must be the same length")
+	}
+
+	buf := make(sdr.SamplesC64, len(left))
+	n, err := sdr.ReadFull(s.reader, buf)
+	if err != nil {
+		return 0, err
+	}
+	buf = buf[:n]
+
+	for i := range buf {
+		phasor := complex128(buf[i])
+		mpx := 0.0
+		if s.havePhasor {
+			mpx = float64(s.disc.step(phasor, s.lastPhasor))
+			if s.disc.squelched() {
+				mpx = 0
+			}
+		}
+		s.lastPhasor = phasor
+		s.havePhasor = true
+
+		// Isolate the pilot and drive the PLL's phase detector with a
+		// product detector: error is (roughly) proportional to
+		// sin(pilotPhase - nco), which is zero at lock.
+		pilot := s.pilotBP.Step(mpx)
+		err := pilot * math.Sin(s.pilot.Phase())
+		s.pilot.Step(err)
+		s.locked = s.lockLPF.Step(math.Abs(err)) < pilotLockThreshold
+
+		if s.rds != nil {
+			s.rds.Step(mpx, s.pilot.Phase(), s.locked)
+		}
+
+		// The 38 kHz subcarrier is exactly double the pilot's phase.
+		carrier38 := math.Cos(2 * s.pilot.Phase())
+
+		sub := s.subBP.Step(mpx)
+		diff := sub * carrier38
+
+		sum := s.sumLPF.Step(mpx)
+		diff = s.diffLPF.Step(diff)
+
+		if !s.locked {
+			diff = 0
+		}
+
+		left[i] = float32(sum + diff)
+		right[i] = float32(sum - diff)
+
+		if s.deemphL != nil {
+			left[i] = s.deemphL.Step(left[i])
+			right[i] = s.deemphR.Step(right[i])
+		}
+	}
+
+	return len(buf), nil
+}
+
+// DemodulateStereo creates a new StereoDemodulator, recovering both audio
+// channels from an FM broadcast multiplex baseband. Use this instead of
+// Demodulate when stereo (pilot-tone) decode is wanted; it always
+// attempts stereo decode, falling back to mono per-sample based on
+// Locked.
+func DemodulateStereo(reader sdr.Reader, cfg DemodulatorConfig) (*StereoDemodulator, error) {
+	mono, err := Demodulate(reader, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mpxRate := float64(mono.SampleRate())
+
+	return &StereoDemodulator{
+		reader:  mono.reader,
+		config:  cfg,
+		disc:    mono.disc,
+		mpxRate: mpxRate,
+
+		pilotBP: filter.BPF(PilotFrequency, mpxRate, PilotFrequency/400),
+		pilot:   internal.NewPLL(mpxRate, PilotFrequency, pilotLoopBW, 0.707),
+		lockLPF: internal.NewOnePoleLowpass(2, mpxRate),
+
+		subBP: filter.BPF(2*PilotFrequency, mpxRate, (2*PilotFrequency)/30000),
+
+		sumLPF:  internal.NewOnePoleLowpass(15000, mpxRate),
+		diffLPF: internal.NewOnePoleLowpass(15000, mpxRate),
+
+		deemphL: newDeemphasisFilter(cfg.Deemphasis, mpxRate),
+		deemphR: newDeemphasisFilter(cfg.Deemphasis, mpxRate),
+	}, nil
+}
+
+// vim: foldmethod=marker